@@ -34,6 +34,7 @@ package bgls
 // KoskVerifyMultiSignatureWithMultiplicity, KoskVerifyAggregateSignature
 
 import (
+	"crypto/rand"
 	"math/big"
 
 	. "github.com/PeterCCLiu/bgls/curves" // nolint: golint
@@ -61,8 +62,13 @@ func CheckAuthentication(curve CurveSystem, pubkey Point, authentication Point)
 }
 
 // CheckAuthenticationCustHash verifies that the provided signature is in fact authentication
-// for this public key.
+// for this public key. An identity or small-subgroup pubkey or authentication
+// is rejected outright; see CheckAuthenticationChecked to distinguish that
+// case from an authentication that simply doesn't verify.
 func CheckAuthenticationCustHash(curve CurveSystem, pubkey Point, authentication Point, hash func([]byte) Point) bool {
+	if validatePoints(curve, pubkey, authentication) != nil {
+		return false
+	}
 	msg := pubkey.Marshal()
 	msg = append(make([]byte, 0), msg...)
 	return VerifySingleSignatureCustHash(curve, authentication, pubkey, msg, hash)
@@ -96,8 +102,17 @@ func KoskVerifySingleSignatureCustHash(curve CurveSystem, pubKey Point, msg []by
 }
 
 // KoskVerifyAggregateSignature verifies that the aggregated signature proves
-// that all messages were signed by the associated keys.
+// that all messages were signed by the associated keys. An identity or
+// small-subgroup key or signature, or keys that sum to the identity or
+// contain a duplicate, are rejected outright; see
+// KoskVerifyAggregateSignatureChecked to distinguish that case from an
+// aggregate that simply doesn't verify. verifyAggSig itself has no way to
+// tell a rogue-key aggregate from a legitimate one, so this check has to
+// happen here, at every caller of it.
 func KoskVerifyAggregateSignature(curve CurveSystem, aggsig Point, keys []Point, msgs [][]byte) bool {
+	if validatePoints(curve, aggsig) != nil || validatePoints(curve, keys...) != nil || checkSplitZero(curve, keys) {
+		return false
+	}
 	newMsgs := make([][]byte, len(msgs))
 	for i := 0; i < len(msgs); i++ {
 		newMsgs[i] = append([]byte{1}, msgs[i]...)
@@ -113,8 +128,17 @@ func (m MultiSig) Verify(curve CurveSystem) bool {
 
 // KoskVerifyMultiSignature checks that the aggregate signature correctly proves
 // that a single message has been signed by a set of keys,
-// vulnerable against chosen key attack, if keys have not been authenticated
+// vulnerable against chosen key attack, if keys have not been authenticated.
+// An identity or small-subgroup key or signature, or keys that sum to the
+// identity or contain a duplicate, are rejected outright; see
+// KoskVerifyMultiSignatureChecked to distinguish that case from a
+// multisignature that simply doesn't verify. verifyMultiSignature itself
+// has no way to tell a rogue-key aggregate from a legitimate one, so this
+// check has to happen here, at every caller of it.
 func KoskVerifyMultiSignature(curve CurveSystem, aggsig Point, keys []Point, msg []byte) bool {
+	if validatePoints(curve, aggsig) != nil || validatePoints(curve, keys...) != nil || checkSplitZero(curve, keys) {
+		return false
+	}
 	msg2 := append([]byte{1}, msg...)
 	return verifyMultiSignature(curve, aggsig, keys, msg2)
 }
@@ -123,13 +147,48 @@ func KoskVerifyMultiSignature(curve CurveSystem, aggsig Point, keys []Point, msg
 // that a set of messages has the correct associated pubkey.
 // vulnerable against chosen key attack, if keys have not been authenticated
 // This is faster than verifying each multisignature individually.
+//
+// Each batch entry is weighted by an independent 128-bit random scalar
+// r_j before being folded into a single equation, so the check becomes
+// e(g, Sum_j(r_j * aggsig_j)) =?= Prod_j e(Sum_i(r_j * pk_j,i), H(msg_j)),
+// verified with one MultiPair call. Combining entries without these
+// random weights would let an attacker mix contributions between
+// messages and forge a batch that passes despite no single entry being
+// valid; the random weights make that exponentially unlikely.
 func KoskVerifyBatchMultiSignature(curve CurveSystem, aggsigs []Point, pubkeys [][]Point, msgs [][]byte) bool {
-	aggsig := AggregateSignatures(aggsigs)
-	keys := make([]Point, len(pubkeys), len(pubkeys))
-	for i := 0; i < len(pubkeys); i++ {
-		keys[i] = AggregateKeys(pubkeys[i])
+	if len(aggsigs) != len(pubkeys) || len(aggsigs) != len(msgs) {
+		return false
+	}
+	weights := make([]*big.Int, len(aggsigs))
+	for j := range aggsigs {
+		weights[j] = randomScalar(curve)
+	}
+	weightedSig := ScaleAndAggregate(aggsigs, weights)
+
+	negOne := new(big.Int).Sub(curve.Order(), big.NewInt(1))
+	negG2 := ScalePoints([]Point{curve.G2()}, []*big.Int{negOne})[0]
+	pairs := []PairInput{{A: negG2, B: weightedSig}}
+	for j, keys := range pubkeys {
+		m := append([]byte{1}, msgs[j]...)
+		factors := make([]*big.Int, len(keys))
+		for i := range keys {
+			factors[i] = weights[j]
+		}
+		weightedKey := ScaleAndAggregate(keys, factors)
+		pairs = append(pairs, PairInput{A: weightedKey, B: curve.HashToG1(m)})
+	}
+	return curve.MultiPair(pairs).IsOne()
+}
+
+// randomScalar draws a uniform 128-bit random scalar, used to weight
+// entries of a randomized batch verification so that an attacker cannot
+// profitably mix contributions between entries.
+func randomScalar(curve CurveSystem) *big.Int {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err) // crypto/rand.Read only fails if the system CSPRNG is broken
 	}
-	return KoskVerifyAggregateSignature(curve, aggsig, keys, msgs)
+	return new(big.Int).SetBytes(buf)
 }
 
 // KoskVerifyMultiSignatureWithMultiplicity verifies a BLS multi signature where
@@ -0,0 +1,170 @@
+// Copyright (C) 2018 Authors
+// distributed under Apache 2.0 license
+
+package bgls
+
+// This file extends the key-generation surface with a deterministic
+// variant of KeyGen, so that keys can be derived from a seed shared with
+// other standards-compliant BLS libraries rather than drawn from the
+// system RNG directly. KeyGenFromSeed implements the IETF BLS draft
+// KeyGen algorithm; DeriveChildSK implements the EIP-2333 derivation
+// tree on top of it, so wallet integrations can use HD-style key trees
+// with Authenticate/KoskSign as usual.
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	. "github.com/PeterCCLiu/bgls/curves" // nolint: golint
+	"golang.org/x/crypto/hkdf"
+)
+
+// keygenL is L from the IETF BLS draft KeyGen algorithm: the number of
+// bytes of HKDF output requested per candidate secret key, chosen so that
+// OS2IP(...) mod r is uniform enough over any curve order the draft
+// targets.
+const keygenL = 48
+
+// saltSeed is the fixed string KeyGenFromSeed iterates SHA-256 over to
+// produce its initial salt, per the IETF BLS draft.
+const saltSeed = "BLS-SIG-KEYGEN-SALT-"
+
+// ErrSeedTooShort is returned by KeyGenFromSeed when ikm is shorter than
+// the 32 bytes the draft requires for adequate entropy.
+var ErrSeedTooShort = errors.New("bgls: ikm must be at least 32 bytes")
+
+// KeyGenFromSeed deterministically derives a secret/public keypair from
+// ikm and keyInfo, implementing the IETF BLS draft KeyGen algorithm: it
+// salts ikm with repeated SHA-256 hashing of the fixed string
+// "BLS-SIG-KEYGEN-SALT-" until HKDF-Extract/Expand yields a nonzero
+// scalar mod the curve's group order. keyInfo may be nil to derive a
+// single master key, or non-nil to derive distinct keys from the same
+// ikm. DeriveChildSK does not use keyInfo: it always passes nil and
+// varies the ikm instead.
+func KeyGenFromSeed(curve CurveSystem, ikm []byte, keyInfo []byte) (*big.Int, Point, error) {
+	if len(ikm) < 32 {
+		return nil, nil, ErrSeedTooShort
+	}
+	sk := deriveKey(curve.Order(), ikm, keyInfo)
+	return sk, LoadPublicKey(curve, sk), nil
+}
+
+// deriveKey runs the salt-iteration / HKDF loop from the IETF BLS draft
+// KeyGen algorithm against group order r, returning a nonzero scalar mod
+// r.
+func deriveKey(r *big.Int, ikm []byte, keyInfo []byte) *big.Int {
+	salt := sha256.Sum256([]byte(saltSeed))
+	ikmZero := append(append([]byte{}, ikm...), 0)
+	info := append(append([]byte{}, keyInfo...), i2osp(keygenL, 2)...)
+
+	for {
+		extracted := hkdfExtract(salt[:], ikmZero)
+		okm := make([]byte, keygenL)
+		if _, err := hkdf.Expand(sha256.New, extracted, info).Read(okm); err != nil {
+			panic(err) // hkdf.Expand.Read only fails if more output is requested than HKDF allows
+		}
+		sk := new(big.Int).Mod(new(big.Int).SetBytes(okm), r)
+		if sk.Sign() != 0 {
+			return sk
+		}
+		next := sha256.Sum256(salt[:])
+		salt = next
+	}
+}
+
+// hkdfExtract runs HKDF-Extract(salt, ikm) directly, since
+// golang.org/x/crypto/hkdf only exposes the combined Extract-then-Expand
+// Reader.
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// i2osp is I2OSP(x, length): the big-endian encoding of x in exactly
+// length bytes.
+func i2osp(x int, length int) []byte {
+	out := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		out[i] = byte(x)
+		x >>= 8
+	}
+	return out
+}
+
+// lamportDepth is the number of 32-byte chunks in an EIP-2333 Lamport
+// secret/public key.
+const lamportDepth = 255
+
+// lamportChunkLen is the length in bytes of a single EIP-2333 Lamport key
+// chunk.
+const lamportChunkLen = 32
+
+// flipBits returns the bitwise complement of b.
+func flipBits(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, v := range b {
+		out[i] = ^v
+	}
+	return out
+}
+
+// ikmToLamportSK implements EIP-2333's IKM_to_lamport_SK: lamportDepth
+// chunks of lamportChunkLen bytes each, expanded from ikm under salt via
+// HKDF-Expand with no key-info string.
+func ikmToLamportSK(ikm, salt []byte) [][]byte {
+	okm := make([]byte, lamportDepth*lamportChunkLen)
+	extracted := hkdfExtract(salt, ikm)
+	if _, err := hkdf.Expand(sha256.New, extracted, nil).Read(okm); err != nil {
+		panic(err) // hkdf.Expand.Read only fails if more output is requested than HKDF allows
+	}
+	chunks := make([][]byte, lamportDepth)
+	for i := range chunks {
+		chunks[i] = okm[i*lamportChunkLen : (i+1)*lamportChunkLen]
+	}
+	return chunks
+}
+
+// parentSKToLamportPK implements EIP-2333's parent_SK_to_lamport_PK: it
+// expands parent twice -- once directly, once bit-flipped -- into two
+// Lamport secret keys salted by index, hashes every chunk of both, and
+// compresses the result into the 32-byte Lamport public key that
+// DeriveChildSK feeds into HKDF_mod_r. Expanding both parent and its
+// complement is what makes the scheme a one-time signature (and
+// therefore safe to use purely as a KDF step here) rather than a
+// directly invertible hash of parent.
+func parentSKToLamportPK(parent *big.Int, index uint32) []byte {
+	salt := make([]byte, 4)
+	binary.BigEndian.PutUint32(salt, index)
+	ikm := parent.FillBytes(make([]byte, 32))
+
+	lamport0 := ikmToLamportSK(ikm, salt)
+	lamport1 := ikmToLamportSK(flipBits(ikm), salt)
+
+	hasher := sha256.New()
+	for _, chunk := range lamport0 {
+		sum := sha256.Sum256(chunk)
+		hasher.Write(sum[:])
+	}
+	for _, chunk := range lamport1 {
+		sum := sha256.Sum256(chunk)
+		hasher.Write(sum[:])
+	}
+	return hasher.Sum(nil)
+}
+
+// DeriveChildSK derives the index-th child of parent using the EIP-2333
+// key derivation tree, so that a single seed can produce an unbounded
+// number of unlinkable keys, each usable with Authenticate/KoskSign as a
+// normal secret key. Per EIP-2333, parent is first run through the
+// Lamport-PK tree step (parent_SK_to_lamport_PK) and only the resulting
+// compressed Lamport public key is fed into HKDF_mod_r; skipping straight
+// to HKDF_mod_r(parent || index) is not EIP-2333 and will not match any
+// other standards-compliant implementation's derived keys.
+func DeriveChildSK(curve CurveSystem, parent *big.Int, index uint32) *big.Int {
+	compressedLamportPK := parentSKToLamportPK(parent, index)
+	return deriveKey(curve.Order(), compressedLamportPK, nil)
+}
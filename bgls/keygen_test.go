@@ -0,0 +1,47 @@
+// Copyright (C) 2018 Authors
+// distributed under Apache 2.0 license
+
+package bgls
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/PeterCCLiu/bgls/curves" // nolint: golint
+)
+
+// TestDeriveChildSKEIP2333Vector checks DeriveChildSK against the test
+// vector published in EIP-2333's reference test cases (seed
+// 0x3141592653589793238462643383279502884197169399375105820974944592,
+// index 3141592653), so this tree interoperates with other
+// implementations rather than just being internally consistent.
+func TestDeriveChildSKEIP2333Vector(t *testing.T) {
+	curve := Bls12_381{}
+	seed, ok := new(big.Int).SetString("3141592653589793238462643383279502884197169399375105820974944592", 16)
+	assert.True(t, ok)
+	wantMaster, ok := new(big.Int).SetString("6083874454709270928345386274498605044986640685124978867557563392430687146096", 10)
+	assert.True(t, ok)
+	wantChild, ok := new(big.Int).SetString("20397789859736650942317412262472558107875392172444076792671091975210932703118", 10)
+	assert.True(t, ok)
+
+	master, _, err := KeyGenFromSeed(curve, seed.FillBytes(make([]byte, 32)), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, master.Cmp(wantMaster))
+
+	child := DeriveChildSK(curve, master, 3141592653)
+	assert.Equal(t, 0, child.Cmp(wantChild))
+}
+
+func TestDeriveChildSKDeterministicAndDistinctPerIndex(t *testing.T) {
+	curve := Bls12_381{}
+	parent := big.NewInt(42)
+
+	child0a := DeriveChildSK(curve, parent, 0)
+	child0b := DeriveChildSK(curve, parent, 0)
+	assert.Equal(t, 0, child0a.Cmp(child0b))
+
+	child1 := DeriveChildSK(curve, parent, 1)
+	assert.NotEqual(t, 0, child0a.Cmp(child1))
+}
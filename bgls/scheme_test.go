@@ -0,0 +1,48 @@
+// Copyright (C) 2018 Authors
+// distributed under Apache 2.0 license
+
+package bgls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/PeterCCLiu/bgls/curves" // nolint: golint
+)
+
+func TestSchemeSignVerifyRoundTrip(t *testing.T) {
+	curve := Bls12_381{}
+	for _, suite := range []CiphersuiteID{Basic, MessageAugmentation, ProofOfPossession} {
+		scheme := NewScheme(curve.Name(), suite)
+		sk, pk, err := KeyGenFromSeed(curve, make([]byte, 32), nil)
+		assert.NoError(t, err)
+		msg := []byte("a message")
+
+		sig := scheme.Sign(curve, sk, msg)
+		assert.True(t, scheme.Verify(curve, pk, msg, sig))
+	}
+}
+
+func TestSchemeAggregateVerifyDuplicateMessages(t *testing.T) {
+	curve := Bls12_381{}
+	msg := []byte("shared message")
+
+	sk1, pk1, err := KeyGenFromSeed(curve, make([]byte, 32), []byte("1"))
+	assert.NoError(t, err)
+	sk2, pk2, err := KeyGenFromSeed(curve, make([]byte, 32), []byte("2"))
+	assert.NoError(t, err)
+	keys := []Point{pk1, pk2}
+	msgs := [][]byte{msg, msg}
+
+	// Basic must reject two signers over the same raw message.
+	basic := NewScheme(curve.Name(), Basic)
+	basicSig := AggregateSignatures([]Point{basic.Sign(curve, sk1, msg), basic.Sign(curve, sk2, msg)})
+	assert.False(t, basic.AggregateVerify(curve, basicSig, keys, msgs))
+
+	// Pop must allow it: its signers are bound by proof of possession
+	// rather than message distinctness.
+	pop := NewScheme(curve.Name(), ProofOfPossession)
+	popSig := AggregateSignatures([]Point{pop.Sign(curve, sk1, msg), pop.Sign(curve, sk2, msg)})
+	assert.True(t, pop.AggregateVerify(curve, popSig, keys, msgs))
+}
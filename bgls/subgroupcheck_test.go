@@ -0,0 +1,79 @@
+// Copyright (C) 2018 Authors
+// distributed under Apache 2.0 license
+
+package bgls
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/PeterCCLiu/bgls/curves" // nolint: golint
+)
+
+// identityG2 returns the identity element of G2, by scaling the
+// generator by zero.
+func identityG2(curve CurveSystem) Point {
+	return ScalePoints([]Point{curve.G2()}, []*big.Int{big.NewInt(0)})[0]
+}
+
+func TestCheckAuthenticationRejectsIdentityPubkey(t *testing.T) {
+	curve := Bls12_381{}
+	sk, _, err := KeyGenFromSeed(curve, make([]byte, 32), nil)
+	assert.NoError(t, err)
+	auth := Authenticate(curve, sk)
+
+	// An attacker who submits the identity point as their "pubkey" would
+	// make e(pk, H(m)) trivially equal to the identity in GT under a
+	// naive check. CheckAuthentication must reject it instead of treating
+	// an unrelated authentication as valid for the identity key.
+	assert.False(t, CheckAuthentication(curve, identityG2(curve), auth))
+}
+
+func TestKoskVerifyMultiSignatureRejectsSplittingZero(t *testing.T) {
+	curve := Bls12_381{}
+	msg := []byte("split the zero")
+
+	sk1, pk1, err := KeyGenFromSeed(curve, make([]byte, 32), []byte("a"))
+	assert.NoError(t, err)
+	sk2, pk2, err := KeyGenFromSeed(curve, make([]byte, 32), []byte("b"))
+	assert.NoError(t, err)
+
+	sig1 := KoskSign(curve, sk1, msg)
+	sig2 := KoskSign(curve, sk2, msg)
+	aggsig := AggregateSignatures([]Point{sig1, sig2})
+
+	// A rogue-key attacker who registers pk2' = -pk1 + pk2 could make the
+	// aggregate public key for {pk1, pk2'} sum to pk2 alone, letting
+	// sk2's signature alone pass as an aggregate over two keys. Simulate
+	// the resulting degenerate aggregate directly: two keys that sum to
+	// the identity.
+	negOne := new(big.Int).Sub(curve.Order(), big.NewInt(1))
+	negPk1 := ScalePoints([]Point{pk1}, []*big.Int{negOne})[0]
+	assert.False(t, KoskVerifyMultiSignature(curve, aggsig, []Point{pk1, negPk1}, msg))
+
+	ok, err := KoskVerifyMultiSignatureChecked(curve, aggsig, []Point{pk1, negPk1}, msg)
+	assert.False(t, ok)
+	assert.Equal(t, errSplitZero, err)
+
+	_ = pk2 // kept for readability of the scenario being simulated above
+}
+
+func TestKoskVerifyAggregateSignatureRejectsDuplicateKey(t *testing.T) {
+	curve := Bls12_381{}
+	sk, pk, err := KeyGenFromSeed(curve, make([]byte, 32), nil)
+	assert.NoError(t, err)
+	msgs := [][]byte{[]byte("m1"), []byte("m2")}
+	sig := KoskSign(curve, sk, msgs[0])
+
+	// The same pubkey appearing twice in an aggregate lets one signer's
+	// contribution double-count; KoskVerifyAggregateSignature must reject
+	// this regardless of what the (mismatched) signature actually is.
+	assert.False(t, KoskVerifyAggregateSignature(curve, sig, []Point{pk, pk}, msgs))
+}
+
+func TestValidatePointsRejectsIdentity(t *testing.T) {
+	curve := Bls12_381{}
+	assert.Error(t, validatePoints(curve, identityG2(curve)))
+}
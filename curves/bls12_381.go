@@ -0,0 +1,318 @@
+// Copyright (C) 2018 Authors
+// distributed under Apache 2.0 license
+
+package curves
+
+// This file adds BLS12-381 as a first-class CurveSystem. The BN curves
+// wired up elsewhere in this package (via go-ethereum's bn256 and
+// dis2/bls12) have no standardized hash-to-curve or wire format, so they
+// can't interoperate with any of the mainstream BLS deployments --
+// Eth2, drand, Coinbase kryptology -- all of which have settled on
+// BLS12-381 G1/G2 with ciphersuite XMD:SHA-256_SSWU_RO. Bls12_381
+// serializes points in the IETF compressed form (48 bytes for G1, 96 for
+// G2) via dis2/bls12's own Marshal/Unmarshal, and hashes to curve under a
+// caller-supplied DST (see HashToG1WithDST and HashToG2WithDST) rather
+// than dis2/bls12's own HashToPoint, which hardcodes its domain tag and so
+// can't produce DST-separated ciphersuite output on its own. The
+// hash-to-curve map is not the SSWU map with isogeny: it is dis2/bls12's
+// FouqueMapXtoY (see below), so hashed points, and signatures over them,
+// differ from those of an XMD:SHA-256_SSWU_RO implementation.
+//
+// Field and group arithmetic, serialization and the pairing are all
+// delegated to dis2/bls12; this file is the CurveSystem adapter, plus a
+// DST-parameterized hash-to-curve built from the same two-encodings-
+// added-together construction dis2/bls12 uses internally (see
+// FouqueMapXtoY and hash.go in that package), just driven by
+// expand_message_xmd(SHA-256) under the caller's DST instead of a fixed
+// tag.
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+
+	bls12 "github.com/dis2/bls12"
+)
+
+// bls12381Order is r, the prime order of the G1/G2 subgroups used by
+// BLS12-381.
+var bls12381Order, _ = new(big.Int).SetString(
+	"73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+// bls12381FieldModulus is p, the base field modulus BLS12-381's Fq (and
+// Fq2 = Fq[u]/(u^2+1)) coordinates are reduced by. This is only used to
+// reduce hash-to-field output into a valid Fq element before handing it
+// to dis2/bls12; it has nothing to do with bls12381Order.
+var bls12381FieldModulus, _ = new(big.Int).SetString(
+	"1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+
+// Bls12_381 is the BLS12-381 CurveSystem: G1 and G2 points backed by
+// dis2/bls12, with IETF-draft-compliant hashing and serialization.
+type Bls12_381 struct{}
+
+// Name returns the ciphersuite-facing name of this curve, used to build
+// default domain separation tags (see NewScheme in package bgls). The IETF/Eth2
+// naming convention labels a suite by the group its signatures are
+// hashed into -- Eth2's 48-byte-pubkey/96-byte-signature suite, with
+// signatures in G2, is "BLS12381G2". This library's convention is the
+// opposite: HashToG1 is what signs messages (see blsKosk.go and
+// scheme.go), so signatures live in G1 and pubkeys in G2, making this
+// "BLS12381G1".
+func (c Bls12_381) Name() string { return "BLS12381G1" }
+
+// Order returns r, the prime order of the G1 and G2 subgroups.
+func (c Bls12_381) Order() *big.Int { return new(big.Int).Set(bls12381Order) }
+
+// G1 returns the standard generator of G1.
+func (c Bls12_381) G1() Point { return &bls12Point{g1: bls12.G1One()} }
+
+// G2 returns the standard generator of G2.
+func (c Bls12_381) G2() Point { return &bls12Point{g2: bls12.G2One()} }
+
+// HashToG1 hashes msg to a point in G1 using the empty domain separation
+// tag. Callers implementing a standards-compliant ciphersuite should
+// prefer HashToG1WithDST.
+func (c Bls12_381) HashToG1(msg []byte) Point {
+	return c.HashToG1WithDST(msg, "")
+}
+
+// HashToG2 hashes msg to a point in G2 using the empty domain separation
+// tag. Callers implementing a standards-compliant ciphersuite should
+// prefer HashToG2WithDST.
+func (c Bls12_381) HashToG2(msg []byte) Point {
+	return c.HashToG2WithDST(msg, "")
+}
+
+// HashToG1WithDST hashes msg to a point in G1 under domain separation tag
+// dst. It hashes to two independent field elements via
+// expand_message_xmd(SHA-256), maps each to a curve point with
+// dis2/bls12's FouqueMapXtoY, and adds the two points together -- the
+// same random-oracle-from-two-encodings construction dis2/bls12's own
+// G1.HashToPoint uses internally, just keyed by dst instead of a
+// hardcoded tag.
+func (c Bls12_381) HashToG1WithDST(msg []byte, dst string) Point {
+	u0, u1 := hashToFq(msg, dst)
+	sum := mapToG1(u0).Add(mapToG1(u1)).(*bls12.G1)
+	sum.ScaleByCofactor()
+	sum.Normalize()
+	return &bls12Point{g1: sum}
+}
+
+// HashToG2WithDST hashes msg to a point in G2 under domain separation tag
+// dst, via the G2 analogue of HashToG1WithDST: two independent Fq2
+// elements mapped with FouqueMapXtoY and added together.
+func (c Bls12_381) HashToG2WithDST(msg []byte, dst string) Point {
+	u0, u1 := hashToFq2(msg, dst)
+	sum := mapToG2(u0).Add(mapToG2(u1)).(*bls12.G2)
+	sum.ScaleByCofactorFast()
+	sum.Normalize()
+	return &bls12Point{g2: sum}
+}
+
+// expandMessageXMD implements expand_message_xmd(msg, DST, lenInBytes)
+// using SHA-256, as defined by the hash-to-curve draft.
+func expandMessageXMD(msg, dst []byte, lenInBytes int) []byte {
+	const bInBytes = sha256.Size
+	ell := (lenInBytes + bInBytes - 1) / bInBytes
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	zPad := make([]byte, sha256.BlockSize)
+	lenBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(lenBytes, uint16(lenInBytes))
+
+	b0Input := append(append(append(zPad, msg...), lenBytes...), append([]byte{0}, dstPrime...)...)
+	b0 := sha256.Sum256(b0Input)
+
+	b1 := sha256.Sum256(append(append(b0[:], 1), dstPrime...))
+	uniformBytes := append([]byte{}, b1[:]...)
+
+	prev := b1
+	for i := 2; i <= ell; i++ {
+		xored := make([]byte, sha256.Size)
+		for j := range xored {
+			xored[j] = b0[j] ^ prev[j]
+		}
+		next := sha256.Sum256(append(append(xored, byte(i)), dstPrime...))
+		uniformBytes = append(uniformBytes, next[:]...)
+		prev = next
+	}
+	return uniformBytes[:lenInBytes]
+}
+
+// hashToFqN draws n field elements for Fq by expanding msg under dst with
+// expand_message_xmd and reducing each 64-byte block mod the field
+// modulus -- the standard hash_to_field construction, with a 64-byte
+// (rather than 48-byte) draw per element so the mod-p bias is
+// cryptographically negligible.
+func hashToFqN(msg []byte, dst string, n int) []*big.Int {
+	const elementLen = 64
+	uniform := expandMessageXMD(msg, []byte(dst), n*elementLen)
+	out := make([]*big.Int, n)
+	for i := range out {
+		raw := new(big.Int).SetBytes(uniform[i*elementLen : (i+1)*elementLen])
+		out[i] = raw.Mod(raw, bls12381FieldModulus)
+	}
+	return out
+}
+
+// hashToFq draws the two independent Fq elements HashToG1WithDST maps to
+// G1 points.
+func hashToFq(msg []byte, dst string) (u0, u1 *big.Int) {
+	elts := hashToFqN(msg, dst, 2)
+	return elts[0], elts[1]
+}
+
+// hashToFq2 draws the two independent Fq2 elements (as (c0, c1) pairs)
+// HashToG2WithDST maps to G2 points.
+func hashToFq2(msg []byte, dst string) (u0, u1 [2]*big.Int) {
+	elts := hashToFqN(msg, dst, 4)
+	return [2]*big.Int{elts[0], elts[1]}, [2]*big.Int{elts[2], elts[3]}
+}
+
+// mapToG1 maps a single Fq element to a (non-subgroup-cleared) G1 point
+// via FouqueMapXtoY, matching the parity convention dis2/bls12's own
+// HashToPoint uses.
+func mapToG1(u *big.Int) *bls12.G1 {
+	t := bls12.FqFromInt(u)
+	var x, y bls12.Fq
+	bls12.FouqueMapXtoY(t, &x, &y)
+	y.CopyParity(t)
+	p := new(bls12.G1)
+	p.SetXY(&x, &y)
+	return p
+}
+
+// mapToG2 maps a single Fq2 element (as a (c0, c1) pair) to a
+// (non-subgroup-cleared) G2 point, the G2 analogue of mapToG1.
+func mapToG2(u [2]*big.Int) *bls12.G2 {
+	t := new(bls12.Fq2).FromInt(u[:]).(*bls12.Fq2)
+	var x, y bls12.Fq2
+	bls12.FouqueMapXtoY(t, &x, &y)
+	y.CopyParity(t)
+	p := new(bls12.G2)
+	p.SetXY(&x, &y)
+	return p
+}
+
+// KeyValidate reports whether pt is a valid BLS12-381 public key or
+// signature: on the curve, not the identity, and a member of the
+// prime-order subgroup. This delegates to dis2/bls12's own Check(), which
+// performs all three directly (including a scalar multiplication by the
+// subgroup order) rather than assuming points handed to this package are
+// already subgroup-clean.
+func (c Bls12_381) KeyValidate(pt Point) bool {
+	p, ok := pt.(*bls12Point)
+	if !ok {
+		return false
+	}
+	switch {
+	case p.g1 != nil:
+		return p.g1.Check()
+	case p.g2 != nil:
+		return p.g2.Check()
+	default:
+		return false
+	}
+}
+
+// UnmarshalG1 parses the 48-byte IETF compressed serialization of a G1
+// point.
+func (c Bls12_381) UnmarshalG1(data []byte) (Point, bool) {
+	g1 := new(bls12.G1)
+	if g1.Unmarshal(data) == nil {
+		return nil, false
+	}
+	return &bls12Point{g1: g1}, true
+}
+
+// UnmarshalG2 parses the 96-byte IETF compressed serialization of a G2
+// point.
+func (c Bls12_381) UnmarshalG2(data []byte) (Point, bool) {
+	g2 := new(bls12.G2)
+	if g2.Unmarshal(data) == nil {
+		return nil, false
+	}
+	return &bls12Point{g2: g2}, true
+}
+
+// PairingProduct reports whether the product of e(pubKeys[i],
+// signatures[i]) over all i is the identity in GT. It delegates to
+// MultiPair, which runs every pairing and folds the results together via
+// the GT group operation.
+func (c Bls12_381) PairingProduct(pubKeys []Point, signatures []Point) bool {
+	if len(pubKeys) != len(signatures) {
+		return false
+	}
+	pairs := make([]PairInput, len(pubKeys))
+	for i := range pubKeys {
+		pairs[i] = PairInput{A: pubKeys[i], B: signatures[i]}
+	}
+	return c.MultiPair(pairs).IsOne()
+}
+
+// bls12Point wraps exactly one of dis2/bls12's G1 or G2 point types,
+// implementing curves.Point for both groups.
+type bls12Point struct {
+	g1 *bls12.G1
+	g2 *bls12.G2
+}
+
+// asG1 returns p's underlying G1 point and true, or nil and false if p
+// actually holds a G2 point.
+func (p *bls12Point) asG1() (*bls12.G1, bool) {
+	return p.g1, p.g1 != nil
+}
+
+// asG2 returns p's underlying G2 point and true, or nil and false if p
+// actually holds a G1 point.
+func (p *bls12Point) asG2() (*bls12.G2, bool) {
+	return p.g2, p.g2 != nil
+}
+
+// Marshal serializes the point to its IETF compressed form: 48 bytes for
+// a G1 point, 96 for G2.
+func (p *bls12Point) Marshal() []byte {
+	if p.g1 != nil {
+		return p.g1.Marshal()
+	}
+	return p.g2.Marshal()
+}
+
+// Add returns the curve addition of p and other, which must be in the
+// same group.
+func (p *bls12Point) Add(other Point) Point {
+	o := other.(*bls12Point)
+	if p.g1 != nil {
+		return &bls12Point{g1: p.g1.Copy().Add(o.g1).(*bls12.G1)}
+	}
+	return &bls12Point{g2: p.g2.Copy().Add(o.g2).(*bls12.G2)}
+}
+
+// Mul returns p scaled by scalar.
+func (p *bls12Point) Mul(scalar *big.Int) Point {
+	s := new(bls12.Scalar).FromInt(scalar)
+	if p.g1 != nil {
+		return &bls12Point{g1: p.g1.Copy().ScalarMult(s).(*bls12.G1)}
+	}
+	return &bls12Point{g2: p.g2.Copy().ScalarMult(s).(*bls12.G2)}
+}
+
+// Copy returns a copy of p.
+func (p *bls12Point) Copy() Point {
+	if p.g1 != nil {
+		return &bls12Point{g1: p.g1.Copy().(*bls12.G1)}
+	}
+	return &bls12Point{g2: p.g2.Copy().(*bls12.G2)}
+}
+
+// Equals reports whether p and other represent the same point.
+func (p *bls12Point) Equals(other Point) bool {
+	o, ok := other.(*bls12Point)
+	if !ok {
+		return false
+	}
+	if p.g1 != nil {
+		return o.g1 != nil && p.g1.Equal(o.g1)
+	}
+	return o.g2 != nil && p.g2.Equal(o.g2)
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2018 Authors
+// distributed under Apache 2.0 license
+
+package curves
+
+import (
+	bls12 "github.com/dis2/bls12"
+)
+
+// PairInput is one (G2, G1) pair to be multiplied into a MultiPair
+// product-of-pairings computation. A must be a G2 point and B a G1
+// point, matching this library's convention throughout (see
+// checkSplitZero and HashToG1 in package bgls): public keys live in G2,
+// signatures and hashed messages live in G1.
+type PairInput struct {
+	A Point
+	B Point
+}
+
+// GT is an element of the pairing target group.
+type GT interface {
+	// Mul returns the product of g and other in GT.
+	Mul(other GT) GT
+	// IsOne reports whether g is the identity of GT.
+	IsOne() bool
+}
+
+// invalidGT is returned by MultiPair when a PairInput holds the wrong
+// concrete type or group for its position. Its IsOne is unconditionally
+// false, so a caller checking MultiPair(pairs).IsOne() rejects rather
+// than silently treating a skipped, malformed term as a satisfied
+// product.
+type invalidGT struct{}
+
+// Mul returns invalidGT unchanged: an invalid product stays invalid no
+// matter what it's multiplied by.
+func (invalidGT) Mul(other GT) GT { return invalidGT{} }
+
+// IsOne is always false for invalidGT.
+func (invalidGT) IsOne() bool { return false }
+
+// gtIdentity is the identity element of GT, obtained by pairing the
+// identity point of G1 against the G2 generator -- bilinearity makes
+// e(O, Q) the identity for any Q, so this doesn't depend on any Fq12
+// literal representation. It's computed once and reused as the
+// comparison target for IsOne and as the empty-product result.
+var gtIdentity = new(bls12.GT).Pair(bls12.G1Zero(), bls12.G2One())
+
+// MultiPair computes the product of e(pair.B, pair.A) over every pair in
+// pairs. dis2/bls12 exposes a pairing only as Pair(p1, p2), a full
+// optimal-ate pairing that already includes final exponentiation, with
+// no separate Miller-loop/final-exponentiation split to fuse across
+// pairs -- so each pair runs its own full pairing, and the results are
+// folded together with the GT group operation (Add, which under the
+// hood is Fq12 multiplication on the already-exponentiated elements;
+// that's exactly what makes combining them this way equal the product of
+// pairings). This is what lets verification of an equation like e(g,
+// sig) =?= Prod_i e(pk_i, H(m_i)) run as one MultiPair call -- pass (-g,
+// sig) alongside the (pk_i, H(m_i)) pairs and check the product equals 1
+// -- instead of checking a pairing equation per term. Any pair whose
+// points aren't the (G2, G1) types PairInput documents makes the whole
+// call return invalidGT rather than silently dropping that term.
+func (c Bls12_381) MultiPair(pairs []PairInput) GT {
+	acc := gtIdentity.Copy()
+	for _, pair := range pairs {
+		pubKeyPoint, ok := pair.A.(*bls12Point)
+		if !ok {
+			return invalidGT{}
+		}
+		sigPoint, ok := pair.B.(*bls12Point)
+		if !ok {
+			return invalidGT{}
+		}
+		g2, ok := pubKeyPoint.asG2()
+		if !ok {
+			return invalidGT{}
+		}
+		g1, ok := sigPoint.asG1()
+		if !ok {
+			return invalidGT{}
+		}
+		acc = acc.Add(new(bls12.GT).Pair(g1, g2))
+	}
+	return bls12GT{acc}
+}
+
+// bls12GT adapts dis2/bls12's GT element to the curves.GT interface.
+type bls12GT struct {
+	elt *bls12.GT
+}
+
+// Mul returns the product of g and other in GT.
+func (g bls12GT) Mul(other GT) GT {
+	o, ok := other.(bls12GT)
+	if !ok {
+		return invalidGT{}
+	}
+	return bls12GT{g.elt.Copy().Add(o.elt)}
+}
+
+// IsOne reports whether g is the identity of GT.
+func (g bls12GT) IsOne() bool {
+	return g.elt.Equal(gtIdentity)
+}